@@ -0,0 +1,324 @@
+// Copyright (c) 2024  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// LineKind identifies the role a LineChange (or intra-line Segment) plays
+// within a unified diff
+type LineKind uint8
+
+const (
+	// LineContext is a line present, unchanged, on both sides
+	LineContext LineKind = iota
+	// LineAdd is a line only present on the changed side
+	LineAdd
+	// LineRemove is a line only present on the source side
+	LineRemove
+	// LineNote is a non-content line such as `\ No newline at end of file`
+	LineNote
+)
+
+// Segment is a portion of a LineChange's Text, the result of diffing a
+// removed/added line pair at the character level
+type Segment struct {
+	Kind LineKind
+	Text string
+}
+
+// LineChange describes a single line of a Hunk
+type LineChange struct {
+	Kind LineKind
+	// OldNo is the 1-based line number in the source file, zero if Kind is LineAdd
+	OldNo int
+	// NewNo is the 1-based line number in the changed file, zero if Kind is LineRemove
+	NewNo int
+	// Text is the line content, without its leading ` `/`-`/`+`/`\` marker
+	Text string
+	// IntraEdits is the character-level diff against this line's paired
+	// add/remove counterpart, if any, broken into equal/changed Segments
+	IntraEdits []Segment
+}
+
+// Hunk is a contiguous block of a FileDiff's changes
+type Hunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	// Header is the hunk's `@@ -a,b +c,d @@` line, including any trailing
+	// section heading text
+	Header string
+	Lines  []LineChange
+}
+
+// FileDiff is one file's worth of a unified diff, parsed into Hunks
+type FileDiff struct {
+	OldPath, NewPath string
+	Hunks            []Hunk
+}
+
+// ChangeSet is a parsed unified diff for a single file, the structured
+// counterpart to the string Diff.Unified output
+type ChangeSet struct {
+	OldPath, NewPath string
+	Hunks            []Hunk
+}
+
+var hunkHeaderRx = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@(.*)$`)
+
+// ParseUnified parses a (possibly multi-file) unified diff into one
+// FileDiff per `--- `/`+++ ` file header pair
+func ParseUnified(unified string) (files []FileDiff, err error) {
+	lines := strings.Split(unified, "\n")
+
+	var current *FileDiff
+	var hunk *Hunk
+
+	closeHunk := func() {
+		if hunk != nil {
+			linkIntraEdits(hunk)
+			current.Hunks = append(current.Hunks, *hunk)
+			hunk = nil
+		}
+	}
+	closeFile := func() {
+		closeHunk()
+		if current != nil {
+			files = append(files, *current)
+			current = nil
+		}
+	}
+
+	for idx := 0; idx < len(lines); idx++ {
+		line := lines[idx]
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			closeFile()
+			current = &FileDiff{OldPath: trimDiffPathPrefix(strings.TrimPrefix(line, "--- "))}
+
+		case strings.HasPrefix(line, "+++ "):
+			if current == nil {
+				err = fmt.Errorf("diff: %q header with no preceding %q header", "+++ ", "--- ")
+				return
+			}
+			current.NewPath = trimDiffPathPrefix(strings.TrimPrefix(line, "+++ "))
+
+		case strings.HasPrefix(line, "@@ "):
+			if current == nil {
+				err = fmt.Errorf("diff: %q header with no preceding file header", "@@ ")
+				return
+			}
+			closeHunk()
+			m := hunkHeaderRx.FindStringSubmatch(line)
+			if m == nil {
+				err = fmt.Errorf("diff: malformed hunk header: %q", line)
+				return
+			}
+			oldStart, _ := strconv.Atoi(m[1])
+			oldLines := 1
+			if m[2] != "" {
+				oldLines, _ = strconv.Atoi(m[2])
+			}
+			newStart, _ := strconv.Atoi(m[3])
+			newLines := 1
+			if m[4] != "" {
+				newLines, _ = strconv.Atoi(m[4])
+			}
+			hunk = &Hunk{OldStart: oldStart, OldLines: oldLines, NewStart: newStart, NewLines: newLines, Header: line}
+
+		case hunk != nil && line != "":
+			switch line[0] {
+			case ' ':
+				hunk.Lines = append(hunk.Lines, LineChange{Kind: LineContext, OldNo: nextOldNo(hunk), NewNo: nextNewNo(hunk), Text: line[1:]})
+			case '-':
+				hunk.Lines = append(hunk.Lines, LineChange{Kind: LineRemove, OldNo: nextOldNo(hunk), Text: line[1:]})
+			case '+':
+				hunk.Lines = append(hunk.Lines, LineChange{Kind: LineAdd, NewNo: nextNewNo(hunk), Text: line[1:]})
+			case '\\':
+				hunk.Lines = append(hunk.Lines, LineChange{Kind: LineNote, Text: line[1:]})
+			}
+
+		default:
+			// blank lines between/after hunks, or trailing newline artifacts
+		}
+	}
+	closeFile()
+	return
+}
+
+// nextOldNo returns the next source line number to assign, based on how
+// many LineContext/LineRemove lines the hunk already has
+func nextOldNo(hunk *Hunk) int {
+	n := hunk.OldStart
+	for _, l := range hunk.Lines {
+		if l.Kind == LineContext || l.Kind == LineRemove {
+			n++
+		}
+	}
+	return n
+}
+
+// nextNewNo returns the next changed line number to assign, based on how
+// many LineContext/LineAdd lines the hunk already has
+func nextNewNo(hunk *Hunk) int {
+	n := hunk.NewStart
+	for _, l := range hunk.Lines {
+		if l.Kind == LineContext || l.Kind == LineAdd {
+			n++
+		}
+	}
+	return n
+}
+
+// trimDiffPathPrefix strips a trailing tab-separated timestamp (as some
+// diff tools add) and a leading `a/`/`b/` path prefix
+func trimDiffPathPrefix(path string) string {
+	if idx := strings.IndexByte(path, '\t'); idx != -1 {
+		path = path[:idx]
+	}
+	path = strings.TrimPrefix(path, "a/")
+	path = strings.TrimPrefix(path, "b/")
+	return path
+}
+
+// lineGroup is one maximal run produced by splitLineGroups: either a
+// consecutive run of removed lines paired with the consecutive run of added
+// lines immediately following it, or a single context/orphan-add/note line
+// that stood on its own
+type lineGroup struct {
+	removed, added []LineChange
+	single         *LineChange
+}
+
+// splitLineGroups segments a hunk's lines into lineGroups, pairing every
+// maximal run of consecutive LineRemove entries with the maximal run of
+// consecutive LineAdd entries immediately following it, and leaving every
+// other line (context, orphan additions, notes) as its own single-line
+// group. removed/added/single all alias lines's backing array, so in-place
+// IntraEdits mutation through them persists. This is the one place that
+// decides how a hunk's lines group into removal/addition pairs, shared by
+// linkIntraEdits, relinkIntraEdits and the inline/side-by-side layout
+// renderers
+func splitLineGroups(lines []LineChange) (groups []lineGroup) {
+	for idx := 0; idx < len(lines); {
+		if lines[idx].Kind != LineRemove {
+			groups = append(groups, lineGroup{single: &lines[idx]})
+			idx++
+			continue
+		}
+		start := idx
+		for idx < len(lines) && lines[idx].Kind == LineRemove {
+			idx++
+		}
+		removed := lines[start:idx]
+		addStart := idx
+		for idx < len(lines) && lines[idx].Kind == LineAdd {
+			idx++
+		}
+		added := lines[addStart:idx]
+		groups = append(groups, lineGroup{removed: removed, added: added})
+	}
+	return
+}
+
+// linkIntraEdits walks a hunk's lines, pairing up consecutive runs of
+// removed lines with the consecutive run of added lines that follows, and
+// fills in each paired line's IntraEdits with its character-level diff
+func linkIntraEdits(hunk *Hunk) {
+	dmp := diffmatchpatch.New()
+	for _, g := range splitLineGroups(hunk.Lines) {
+		if g.single != nil {
+			continue
+		}
+		removed, added := g.removed, g.added
+		paired := len(removed)
+		if len(added) < paired {
+			paired = len(added)
+		}
+		for i := 0; i < paired; i++ {
+			diffs := dmp.DiffMain(removed[i].Text, added[i].Text, false)
+			for _, d := range diffs {
+				switch d.Type {
+				case diffmatchpatch.DiffDelete:
+					removed[i].IntraEdits = append(removed[i].IntraEdits, Segment{Kind: LineRemove, Text: d.Text})
+				case diffmatchpatch.DiffInsert:
+					added[i].IntraEdits = append(added[i].IntraEdits, Segment{Kind: LineAdd, Text: d.Text})
+				case diffmatchpatch.DiffEqual:
+					removed[i].IntraEdits = append(removed[i].IntraEdits, Segment{Kind: LineContext, Text: d.Text})
+					added[i].IntraEdits = append(added[i].IntraEdits, Segment{Kind: LineContext, Text: d.Text})
+				}
+			}
+		}
+	}
+}
+
+// relinkIntraEdits redoes a hunk's IntraEdits pairing at the receiver's
+// configured Granularity, overriding the default char-level pairing
+// ParseUnified already performed. It is a no-op for GranularityChar.
+func (r *CRender) relinkIntraEdits(hunk *Hunk) {
+	if r.Granularity == GranularityChar {
+		return
+	}
+	for _, g := range splitLineGroups(hunk.Lines) {
+		if g.single != nil {
+			continue
+		}
+		removed, added := g.removed, g.added
+		paired := len(removed)
+		if len(added) < paired {
+			paired = len(added)
+		}
+		for i := 0; i < paired; i++ {
+			removed[i].IntraEdits, added[i].IntraEdits = r.diffSegments(removed[i].Text, added[i].Text)
+		}
+	}
+}
+
+// RenderChangeSet serializes a ChangeSet back into literal unified diff
+// text, the inverse of ParseUnified. This is meant for structured
+// consumers (JSON APIs, TUI widgets, patch-editing tools) that built or
+// edited a ChangeSet programmatically and need plain unified diff text
+// back out, not styled markup
+func RenderChangeSet(cs ChangeSet) (unified string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n", cs.OldPath)
+	fmt.Fprintf(&b, "+++ b/%s\n", cs.NewPath)
+	for _, hunk := range cs.Hunks {
+		b.WriteString(hunk.Header)
+		b.WriteByte('\n')
+		for _, line := range hunk.Lines {
+			switch line.Kind {
+			case LineAdd:
+				b.WriteByte('+')
+			case LineRemove:
+				b.WriteByte('-')
+			case LineNote:
+				b.WriteByte('\\')
+			default:
+				b.WriteByte(' ')
+			}
+			b.WriteString(line.Text)
+			b.WriteByte('\n')
+		}
+	}
+	unified = strings.TrimSuffix(b.String(), "\n")
+	return
+}
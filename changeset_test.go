@@ -0,0 +1,105 @@
+// Copyright (c) 2024  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import "testing"
+
+func TestParseUnifiedSingleFile(t *testing.T) {
+	unified := "--- a/foo.go\n+++ b/foo.go\n@@ -1,2 +1,2 @@\n-foo old\n+foo new\n context\n"
+
+	files, err := ParseUnified(unified)
+	if err != nil {
+		t.Fatalf("ParseUnified: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(files))
+	}
+	f := files[0]
+	if f.OldPath != "foo.go" || f.NewPath != "foo.go" {
+		t.Fatalf("unexpected paths: %+v", f)
+	}
+	if len(f.Hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d", len(f.Hunks))
+	}
+	lines := f.Hunks[0].Lines
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(lines))
+	}
+	if lines[0].Kind != LineRemove || lines[0].Text != "foo old" || lines[0].OldNo != 1 {
+		t.Fatalf("unexpected removed line: %+v", lines[0])
+	}
+	if lines[1].Kind != LineAdd || lines[1].Text != "foo new" || lines[1].NewNo != 1 {
+		t.Fatalf("unexpected added line: %+v", lines[1])
+	}
+	if lines[2].Kind != LineContext || lines[2].OldNo != 2 || lines[2].NewNo != 2 {
+		t.Fatalf("unexpected context line: %+v", lines[2])
+	}
+	// char-level intra-edits should have linked the removed/added pair
+	if len(lines[0].IntraEdits) == 0 || len(lines[1].IntraEdits) == 0 {
+		t.Fatalf("expected IntraEdits to be populated on the paired lines")
+	}
+}
+
+func TestParseUnifiedMultiFile(t *testing.T) {
+	unified := "--- a/foo.go\n+++ b/foo.go\n@@ -1,1 +1,1 @@\n-foo old\n+foo new\n" +
+		"--- a/bar.go\n+++ b/bar.go\n@@ -1,1 +1,1 @@\n-bar old\n+bar new\n"
+
+	files, err := ParseUnified(unified)
+	if err != nil {
+		t.Fatalf("ParseUnified: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+	if files[0].NewPath != "foo.go" || files[1].NewPath != "bar.go" {
+		t.Fatalf("unexpected file order/paths: %q, %q", files[0].NewPath, files[1].NewPath)
+	}
+	if len(files[0].Hunks) != 1 || len(files[1].Hunks) != 1 {
+		t.Fatalf("expected 1 hunk per file, got %d and %d", len(files[0].Hunks), len(files[1].Hunks))
+	}
+	if files[0].Hunks[0].Lines[1].Text != "foo new" || files[1].Hunks[0].Lines[1].Text != "bar new" {
+		t.Fatalf("unexpected hunk content: %+v / %+v", files[0].Hunks[0], files[1].Hunks[0])
+	}
+}
+
+func TestParseUnifiedMalformedHunkHeader(t *testing.T) {
+	unified := "--- a/foo.go\n+++ b/foo.go\n@@ not a hunk header @@\n-old\n+new\n"
+	if _, err := ParseUnified(unified); err == nil {
+		t.Fatal("expected an error for a malformed hunk header")
+	}
+}
+
+func TestParseUnifiedMissingFileHeader(t *testing.T) {
+	if _, err := ParseUnified("+++ b/foo.go\n"); err == nil {
+		t.Fatal("expected an error for a +++ header with no preceding --- header")
+	}
+	if _, err := ParseUnified("@@ -1,1 +1,1 @@\n-old\n+new\n"); err == nil {
+		t.Fatal("expected an error for a hunk header with no preceding file header")
+	}
+}
+
+func TestRenderChangeSetRoundTrip(t *testing.T) {
+	unified := "--- a/foo.go\n+++ b/foo.go\n@@ -1,2 +1,2 @@\n-foo old\n+foo new\n context\n"
+
+	files, err := ParseUnified(unified)
+	if err != nil {
+		t.Fatalf("ParseUnified: %v", err)
+	}
+	cs := ChangeSet{OldPath: files[0].OldPath, NewPath: files[0].NewPath, Hunks: files[0].Hunks}
+	rendered := RenderChangeSet(cs)
+	if want := "--- a/foo.go\n+++ b/foo.go\n@@ -1,2 +1,2 @@\n-foo old\n+foo new\n context"; rendered != want {
+		t.Fatalf("RenderChangeSet() = %q, want %q", rendered, want)
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright (c) 2024  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/myers"
+	"github.com/hexops/gotextdiff/span"
+)
+
+// Algorithm selects the line-diffing strategy Diff.init uses to compute edits
+type Algorithm uint8
+
+const (
+	// AlgorithmMyers is the classic shortest-edit-script algorithm, and the
+	// package default
+	AlgorithmMyers Algorithm = iota
+	// AlgorithmPatience anchors on lines that occur exactly once on both
+	// sides, recursing Myers between the anchors. It produces noticeably
+	// better hunks than Myers for reordered blocks
+	AlgorithmPatience
+	// AlgorithmHistogram is patience diff with a frequency-ranked fallback
+	// anchor when no line is unique to both sides, so it degrades more
+	// gracefully than patience on files with few unique lines
+	AlgorithmHistogram
+)
+
+// Options configures how a Diff computes its edits
+type Options struct {
+	// Algorithm selects the line-diffing strategy, defaulting to AlgorithmMyers
+	Algorithm Algorithm
+	// IgnoreWhitespace selects which whitespace differences are treated as
+	// insignificant during edit discovery, defaulting to none. The edits
+	// produced still carry the original, unnormalized text, so
+	// UnifiedEdits and ModifiedEdits apply cleanly regardless of this
+	// setting
+	IgnoreWhitespace WhitespaceMode
+}
+
+// anchor is a pair of line indices, one into a's lines and one into b's
+// lines, known to refer to the same line of content
+type anchor struct {
+	aIdx, bIdx int
+}
+
+// computeEdits dispatches to the configured Algorithm, always returning
+// []gotextdiff.TextEdit so the rest of the Diff API is unaffected by the
+// choice of algorithm
+func computeEdits(opts Options, uri span.URI, before, after string) []gotextdiff.TextEdit {
+	switch opts.Algorithm {
+	case AlgorithmPatience:
+		return patienceEdits(uri, before, after, false)
+	case AlgorithmHistogram:
+		return patienceEdits(uri, before, after, true)
+	default:
+		return myers.ComputeEdits(uri, before, after)
+	}
+}
+
+// splitLines splits text into lines, each retaining its trailing "\n" (the
+// last line won't have one if text doesn't end in a newline), matching
+// gotextdiff/myers's own splitLines so line numbers line up with the Spans
+// myers.ComputeEdits produces
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// patienceEdits implements patience diff (and, with histogram set, a
+// histogram-diff fallback): find lines common to both sides, align them in
+// order, and recurse Myers between the aligned anchors
+func patienceEdits(uri span.URI, before, after string, histogram bool) []gotextdiff.TextEdit {
+	aLines := splitLines(before)
+	bLines := splitLines(after)
+	return diffRange(uri, aLines, bLines, 0, len(aLines), 0, len(bLines), histogram)
+}
+
+// diffRange recursively anchors and diffs the line range [aStart,aEnd) of
+// aLines against [bStart,bEnd) of bLines
+func diffRange(uri span.URI, aLines, bLines []string, aStart, aEnd, bStart, bEnd int, histogram bool) (edits []gotextdiff.TextEdit) {
+	if aStart >= aEnd && bStart >= bEnd {
+		return nil
+	}
+
+	anchors := findAnchors(aLines, bLines, aStart, aEnd, bStart, bEnd, histogram)
+	if len(anchors) == 0 {
+		return diffWithMyers(uri, aLines, bLines, aStart, aEnd, bStart, bEnd)
+	}
+
+	pa, pb := aStart, bStart
+	for _, anc := range anchors {
+		edits = append(edits, diffRange(uri, aLines, bLines, pa, anc.aIdx, pb, anc.bIdx, histogram)...)
+		pa, pb = anc.aIdx+1, anc.bIdx+1
+	}
+	edits = append(edits, diffRange(uri, aLines, bLines, pa, aEnd, pb, bEnd, histogram)...)
+	return
+}
+
+// diffWithMyers runs the standard Myers algorithm over a sub-range of
+// lines, then shifts the resulting edits' line numbers to be relative to
+// the whole document instead of the sub-range
+func diffWithMyers(uri span.URI, aLines, bLines []string, aStart, aEnd, bStart, bEnd int) []gotextdiff.TextEdit {
+	subBefore := strings.Join(aLines[aStart:aEnd], "")
+	subAfter := strings.Join(bLines[bStart:bEnd], "")
+	edits := myers.ComputeEdits(uri, subBefore, subAfter)
+	for i, edit := range edits {
+		start := edit.Span.Start()
+		end := edit.Span.End()
+		edits[i].Span = span.New(uri,
+			span.NewPoint(start.Line()+aStart, 1, 0),
+			span.NewPoint(end.Line()+aStart, 1, 0))
+	}
+	return edits
+}
+
+// findAnchors locates lines common to both sides of the range and returns
+// them as a strictly-increasing (in both aIdx and bIdx) sequence of anchors,
+// preferring lines that are unique to both sides (patience diff's anchors).
+// With histogram set, and no unique line available, it falls back to the
+// single lowest-frequency common line as one anchor so recursion can still
+// proceed on files with few or no unique lines
+func findAnchors(aLines, bLines []string, aStart, aEnd, bStart, bEnd int, histogram bool) []anchor {
+	countA := make(map[string]int, aEnd-aStart)
+	firstA := make(map[string]int, aEnd-aStart)
+	for i := aStart; i < aEnd; i++ {
+		if countA[aLines[i]] == 0 {
+			firstA[aLines[i]] = i
+		}
+		countA[aLines[i]]++
+	}
+	countB := make(map[string]int, bEnd-bStart)
+	firstB := make(map[string]int, bEnd-bStart)
+	for i := bStart; i < bEnd; i++ {
+		if countB[bLines[i]] == 0 {
+			firstB[bLines[i]] = i
+		}
+		countB[bLines[i]]++
+	}
+
+	var unique []anchor
+	for line, ca := range countA {
+		if ca == 1 && countB[line] == 1 {
+			unique = append(unique, anchor{aIdx: firstA[line], bIdx: firstB[line]})
+		}
+	}
+	if len(unique) > 0 {
+		sort.Slice(unique, func(i, j int) bool { return unique[i].aIdx < unique[j].aIdx })
+		return longestIncreasingByB(unique)
+	}
+	if !histogram {
+		return nil
+	}
+
+	// histogram fallback: the common line with the lowest combined
+	// occurrence count, used as a single anchor to keep recursing
+	best := ""
+	bestCount := 0
+	haveBest := false
+	for line, ca := range countA {
+		cb, present := countB[line]
+		if !present {
+			continue
+		}
+		total := ca + cb
+		if !haveBest || total < bestCount {
+			best, bestCount, haveBest = line, total, true
+		}
+	}
+	if !haveBest {
+		return nil
+	}
+	return []anchor{{aIdx: firstA[best], bIdx: firstB[best]}}
+}
+
+// longestIncreasingByB returns the longest subsequence of anchors (already
+// sorted by aIdx) whose bIdx values are strictly increasing, using
+// patience-sort style binary search so it runs in O(n log n)
+func longestIncreasingByB(anchors []anchor) []anchor {
+	tails := make([]int, 0, len(anchors)) // indices into anchors, tails[k] = end of best run of length k+1
+	prev := make([]int, len(anchors))
+
+	for i, a := range anchors {
+		pos := sort.Search(len(tails), func(k int) bool { return anchors[tails[k]].bIdx >= a.bIdx })
+		if pos > 0 {
+			prev[i] = tails[pos-1]
+		} else {
+			prev[i] = -1
+		}
+		if pos == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[pos] = i
+		}
+	}
+
+	if len(tails) == 0 {
+		return nil
+	}
+	result := make([]anchor, len(tails))
+	k := tails[len(tails)-1]
+	for i := len(tails) - 1; i >= 0; i-- {
+		result[i] = anchors[k]
+		k = prev[k]
+	}
+	return result
+}
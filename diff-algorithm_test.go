@@ -0,0 +1,122 @@
+// Copyright (c) 2024  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/span"
+)
+
+func TestFindAnchorsPrefersUniqueLines(t *testing.T) {
+	aLines := []string{"a\n", "b\n", "c\n"}
+	bLines := []string{"a\n", "x\n", "b\n", "c\n"}
+
+	anchors := findAnchors(aLines, bLines, 0, len(aLines), 0, len(bLines), false)
+	want := []anchor{{aIdx: 0, bIdx: 0}, {aIdx: 1, bIdx: 2}, {aIdx: 2, bIdx: 3}}
+	if !reflect.DeepEqual(anchors, want) {
+		t.Fatalf("findAnchors() = %+v, want %+v", anchors, want)
+	}
+}
+
+func TestFindAnchorsNoUniqueLineWithoutHistogram(t *testing.T) {
+	aLines := []string{"dup\n", "dup\n"}
+	bLines := []string{"dup\n", "dup\n"}
+
+	if anchors := findAnchors(aLines, bLines, 0, len(aLines), 0, len(bLines), false); anchors != nil {
+		t.Fatalf("expected no anchors without a unique line and histogram disabled, got %+v", anchors)
+	}
+}
+
+func TestFindAnchorsHistogramFallback(t *testing.T) {
+	// "dup" appears twice on both sides (no unique line), "rare" appears
+	// twice on the a side and once on the b side: lowest combined count
+	aLines := []string{"dup\n", "rare\n", "dup\n", "rare\n"}
+	bLines := []string{"dup\n", "rare\n", "dup\n"}
+
+	anchors := findAnchors(aLines, bLines, 0, len(aLines), 0, len(bLines), true)
+	if len(anchors) != 1 {
+		t.Fatalf("expected exactly 1 histogram-fallback anchor, got %+v", anchors)
+	}
+	if anchors[0] != (anchor{aIdx: 1, bIdx: 1}) {
+		t.Fatalf("expected the fallback anchor to be the first \"rare\" line, got %+v", anchors[0])
+	}
+}
+
+func TestLongestIncreasingByB(t *testing.T) {
+	// bIdx sequence 3,1,2,0,4 -> longest increasing run is 1,2,4 (by value)
+	anchors := []anchor{
+		{aIdx: 0, bIdx: 3},
+		{aIdx: 1, bIdx: 1},
+		{aIdx: 2, bIdx: 2},
+		{aIdx: 3, bIdx: 0},
+		{aIdx: 4, bIdx: 4},
+	}
+	got := longestIncreasingByB(anchors)
+	want := []anchor{{aIdx: 1, bIdx: 1}, {aIdx: 2, bIdx: 2}, {aIdx: 4, bIdx: 4}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("longestIncreasingByB() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLongestIncreasingByBEmpty(t *testing.T) {
+	if got := longestIncreasingByB(nil); got != nil {
+		t.Fatalf("expected nil for an empty input, got %+v", got)
+	}
+}
+
+// TestPatienceEditsReorderedBlock is patience diff's canonical improvement
+// over Myers: when a block of lines moves, Myers tends to report a long run
+// of spurious small edits, while patience anchors on the unique lines
+// surrounding the move and reports the move itself as one clean delete plus
+// one clean insert of the moved line
+func TestPatienceEditsReorderedBlock(t *testing.T) {
+	before := "unique1\ncommon\nunique2\n"
+	after := "unique1\nunique2\ncommon\n"
+
+	edits := computeEdits(Options{Algorithm: AlgorithmPatience}, span.URIFromPath("test.txt"), before, after)
+	after2 := gotextdiff.ApplyEdits(before, edits)
+	if after2 != after {
+		t.Fatalf("applying patience edits produced %q, want %q", after2, after)
+	}
+	// the two unique lines anchor the diff, so only "common" should move:
+	// a delete at its old position and an insert at its new one, with
+	// nothing said about unique1/unique2 at all
+	if len(edits) != 2 {
+		t.Fatalf("expected exactly 2 edits (delete + insert) around the moved \"common\" line, got %d: %+v", len(edits), edits)
+	}
+}
+
+func TestHistogramEditsNoUniqueLines(t *testing.T) {
+	before := "dup\nrare\ndup\nrare\n"
+	after := "dup\nrare\ndup\n"
+
+	edits := computeEdits(Options{Algorithm: AlgorithmHistogram}, span.URIFromPath("test.txt"), before, after)
+	got := gotextdiff.ApplyEdits(before, edits)
+	if got != after {
+		t.Fatalf("applying histogram edits produced %q, want %q", got, after)
+	}
+}
+
+func TestComputeEditsDefaultsToMyers(t *testing.T) {
+	before := "a\nb\nc\n"
+	after := "a\nx\nc\n"
+	edits := computeEdits(Options{}, span.URIFromPath("test.txt"), before, after)
+	if got := gotextdiff.ApplyEdits(before, edits); got != after {
+		t.Fatalf("applying default-algorithm edits produced %q, want %q", got, after)
+	}
+}
@@ -16,9 +16,9 @@ package diff
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/hexops/gotextdiff"
-	"github.com/hexops/gotextdiff/myers"
 	"github.com/hexops/gotextdiff/span"
 
 	"github.com/go-corelibs/maps"
@@ -28,6 +28,7 @@ type Diff struct {
 	path    string
 	source  string
 	changed string
+	opts    Options
 	edits   []gotextdiff.TextEdit
 	keep    map[int]struct{}
 	groups  [][]int
@@ -35,19 +36,50 @@ type Diff struct {
 
 // New constructs a new Diff instance with the given source and changed
 // strings computed into a set of "edits" which can be selectively
-// included in the Diff.UnifiedEdits and Diff.ModifiedEdits outputs
+// included in the Diff.UnifiedEdits and Diff.ModifiedEdits outputs.
+// New is equivalent to NewWithOptions with the zero Options value, and so
+// always diffs with AlgorithmMyers
 func New(path, source, changed string) (delta *Diff) {
+	return NewWithOptions(path, source, changed, Options{})
+}
+
+// NewWithOptions is New with explicit Options, used to select the
+// line-diffing Algorithm and which whitespace differences to ignore
+func NewWithOptions(path, source, changed string, opts Options) (delta *Diff) {
 	delta = new(Diff)
 	delta.path = path
 	delta.source = source
 	delta.changed = changed
+	delta.opts = opts
 	delta.keep = make(map[int]struct{})
 	delta.init()
 	return
 }
 
+// NewFromReaders is New, reading source and changed from io.Reader instead
+// of requiring the caller to buffer them into strings first
+func NewFromReaders(path string, source, changed io.Reader) (delta *Diff, err error) {
+	var sourceBytes, changedBytes []byte
+	if sourceBytes, err = io.ReadAll(source); err != nil {
+		return
+	}
+	if changedBytes, err = io.ReadAll(changed); err != nil {
+		return
+	}
+	delta = New(path, string(sourceBytes), string(changedBytes))
+	return
+}
+
 func (d *Diff) init() {
-	d.edits = myers.ComputeEdits(span.URIFromPath(d.path), d.source, d.changed)
+	source, changed := d.source, d.changed
+	if d.opts.IgnoreWhitespace != 0 {
+		source = normalizeWhitespace(source, d.opts.IgnoreWhitespace)
+		changed = normalizeWhitespace(changed, d.opts.IgnoreWhitespace)
+	}
+	d.edits = computeEdits(d.opts, span.URIFromPath(d.path), source, changed)
+	if d.opts.IgnoreWhitespace != 0 {
+		d.edits = restoreOriginalText(d.edits, d.source, d.changed)
+	}
 	d.groups = make([][]int, 0)
 	previousLine := -1
 	var group []int
@@ -0,0 +1,301 @@
+// Copyright (c) 2024  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"strings"
+
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/myers"
+	"github.com/hexops/gotextdiff/span"
+)
+
+// Conflict is one region where ours and theirs both changed the same part
+// of base in incompatible ways
+type Conflict struct {
+	// Base is base's content for this region
+	Base string
+	// Ours is ours' content for this region
+	Ours string
+	// Theirs is theirs' content for this region
+	Theirs string
+
+	resolved bool
+	text     string
+}
+
+// MergeOptions configures how a Merge renders its conflict markers
+type MergeOptions struct {
+	// ShowBase includes a `||||||| base` section in each conflict's marker
+	// block, defaulting to false, matching git's default merge markers
+	ShowBase bool
+}
+
+type mergePart struct {
+	text     string // set for clean, auto-merged text
+	conflict *Conflict
+}
+
+// Merge is a three-way (diff3-style) merge of ours and theirs against their
+// common base
+type Merge struct {
+	path               string
+	base, ours, theirs string
+	opts               MergeOptions
+	parts              []mergePart
+	conflicts          []*Conflict
+}
+
+// NewMerge constructs a three-way merge of ours and theirs against base,
+// computed as diff3: edits for base→ours and base→theirs are computed with
+// the same myers backend, walked in lockstep by base offset, auto-merging
+// non-overlapping regions and recording a Conflict for each region both
+// sides changed incompatibly. NewMerge is equivalent to NewMergeWithOptions
+// with the zero MergeOptions value
+func NewMerge(path, base, ours, theirs string) (merge *Merge) {
+	return NewMergeWithOptions(path, base, ours, theirs, MergeOptions{})
+}
+
+// NewMergeWithOptions is NewMerge with explicit MergeOptions
+func NewMergeWithOptions(path, base, ours, theirs string, opts MergeOptions) (merge *Merge) {
+	merge = &Merge{path: path, base: base, ours: ours, theirs: theirs, opts: opts}
+	merge.init()
+	return
+}
+
+func (m *Merge) init() {
+	uri := span.URIFromPath(m.path)
+	oursEdits := myers.ComputeEdits(uri, m.base, m.ours)
+	theirsEdits := myers.ComputeEdits(uri, m.base, m.theirs)
+	baseLines := splitLines(m.base)
+
+	basePos, oidx, tidx := 0, 0, 0
+	for oidx < len(oursEdits) || tidx < len(theirsEdits) {
+		nextStart, ok := nextEditStart(oursEdits, oidx, theirsEdits, tidx)
+		if !ok {
+			break
+		}
+		if nextStart > basePos {
+			m.appendClean(strings.Join(baseLines[basePos:nextStart], ""))
+			basePos = nextStart
+		}
+
+		// myers often expresses a single logical line replacement as two
+		// adjacent edits on the same side (a delete immediately followed
+		// by a zero-width insert), so same-side edits that merely touch
+		// must still be chained into one cluster; only pull in the other
+		// side once its edits actually overlap a base line already
+		// claimed by this cluster
+		clusterStart := basePos
+		clusterEnd := clusterStart
+		oStartIdx, tStartIdx := oidx, tidx
+		if oidx < len(oursEdits) && oursEdits[oidx].Span.Start().Line()-1 == clusterStart {
+			oidx, clusterEnd = chainSameSide(oursEdits, oidx, clusterEnd)
+		}
+		if tidx < len(theirsEdits) && theirsEdits[tidx].Span.Start().Line()-1 == clusterStart {
+			tidx, clusterEnd = chainSameSide(theirsEdits, tidx, clusterEnd)
+		}
+		for {
+			advanced := false
+			if oidx < len(oursEdits) && oursEdits[oidx].Span.Start().Line()-1 < clusterEnd {
+				oidx, clusterEnd = chainSameSide(oursEdits, oidx, clusterEnd)
+				advanced = true
+			}
+			if tidx < len(theirsEdits) && theirsEdits[tidx].Span.Start().Line()-1 < clusterEnd {
+				tidx, clusterEnd = chainSameSide(theirsEdits, tidx, clusterEnd)
+				advanced = true
+			}
+			if !advanced {
+				break
+			}
+		}
+
+		oursInCluster := oursEdits[oStartIdx:oidx]
+		theirsInCluster := theirsEdits[tStartIdx:tidx]
+
+		switch {
+		case len(oursInCluster) == 0:
+			m.appendClean(buildSideText(baseLines, theirsInCluster, basePos, clusterEnd))
+		case len(theirsInCluster) == 0:
+			m.appendClean(buildSideText(baseLines, oursInCluster, basePos, clusterEnd))
+		default:
+			oursText := buildSideText(baseLines, oursInCluster, basePos, clusterEnd)
+			theirsText := buildSideText(baseLines, theirsInCluster, basePos, clusterEnd)
+			if oursText == theirsText {
+				m.appendClean(oursText)
+			} else {
+				m.appendConflict(&Conflict{
+					Base:   strings.Join(baseLines[basePos:clusterEnd], ""),
+					Ours:   oursText,
+					Theirs: theirsText,
+				})
+			}
+		}
+
+		basePos = clusterEnd
+	}
+	if basePos < len(baseLines) {
+		m.appendClean(strings.Join(baseLines[basePos:], ""))
+	}
+}
+
+// chainSameSide advances idx past every edit in edits that starts at or
+// before end, extending end to cover each one in turn. Edits from the same
+// side that merely touch (one starts exactly where the previous one ends)
+// are always chained together, since that's how myers represents a single
+// line replacement: a delete immediately followed by a zero-width insert
+func chainSameSide(edits []gotextdiff.TextEdit, idx, end int) (newIdx, newEnd int) {
+	newIdx, newEnd = idx, end
+	for newIdx < len(edits) && edits[newIdx].Span.Start().Line()-1 <= newEnd {
+		if e := edits[newIdx].Span.End().Line() - 1; e > newEnd {
+			newEnd = e
+		}
+		newIdx++
+	}
+	return
+}
+
+// nextEditStart returns the smaller of oursEdits[oidx] and
+// theirsEdits[tidx]'s base start line (0-based), and false if both lists
+// are exhausted
+func nextEditStart(oursEdits []gotextdiff.TextEdit, oidx int, theirsEdits []gotextdiff.TextEdit, tidx int) (start int, ok bool) {
+	hasO := oidx < len(oursEdits)
+	hasT := tidx < len(theirsEdits)
+	switch {
+	case hasO && hasT:
+		oStart := oursEdits[oidx].Span.Start().Line() - 1
+		tStart := theirsEdits[tidx].Span.Start().Line() - 1
+		return min(oStart, tStart), true
+	case hasO:
+		return oursEdits[oidx].Span.Start().Line() - 1, true
+	case hasT:
+		return theirsEdits[tidx].Span.Start().Line() - 1, true
+	default:
+		return 0, false
+	}
+}
+
+// buildSideText replays edits (a contiguous slice from one side's edit
+// list) over baseLines[clusterStart:clusterEnd], producing that side's
+// content for the cluster
+func buildSideText(baseLines []string, edits []gotextdiff.TextEdit, clusterStart, clusterEnd int) string {
+	var b strings.Builder
+	pos := clusterStart
+	for _, e := range edits {
+		start := e.Span.Start().Line() - 1
+		end := e.Span.End().Line() - 1
+		b.WriteString(strings.Join(baseLines[pos:start], ""))
+		b.WriteString(e.NewText)
+		pos = end
+	}
+	b.WriteString(strings.Join(baseLines[pos:clusterEnd], ""))
+	return b.String()
+}
+
+func (m *Merge) appendClean(text string) {
+	if text == "" {
+		return
+	}
+	m.parts = append(m.parts, mergePart{text: text})
+}
+
+func (m *Merge) appendConflict(c *Conflict) {
+	m.conflicts = append(m.conflicts, c)
+	m.parts = append(m.parts, mergePart{conflict: c})
+}
+
+// Conflicts returns a snapshot of every conflicted region, in document order
+func (m *Merge) Conflicts() (conflicts []Conflict) {
+	conflicts = make([]Conflict, len(m.conflicts))
+	for idx, c := range m.conflicts {
+		conflicts[idx] = *c
+	}
+	return
+}
+
+// KeepOurs resolves the conflict at index with its Ours content
+func (m *Merge) KeepOurs(index int) (ok bool) {
+	if ok = index >= 0 && index < len(m.conflicts); ok {
+		m.conflicts[index].resolved = true
+		m.conflicts[index].text = m.conflicts[index].Ours
+	}
+	return
+}
+
+// KeepTheirs resolves the conflict at index with its Theirs content
+func (m *Merge) KeepTheirs(index int) (ok bool) {
+	if ok = index >= 0 && index < len(m.conflicts); ok {
+		m.conflicts[index].resolved = true
+		m.conflicts[index].text = m.conflicts[index].Theirs
+	}
+	return
+}
+
+// SetResolution resolves the conflict at index with arbitrary text
+func (m *Merge) SetResolution(index int, text string) (ok bool) {
+	if ok = index >= 0 && index < len(m.conflicts); ok {
+		m.conflicts[index].resolved = true
+		m.conflicts[index].text = text
+	}
+	return
+}
+
+// Resolved returns the fully merged text and true if every conflict has
+// been resolved (via KeepOurs, KeepTheirs or SetResolution); otherwise
+// returns ("", false)
+func (m *Merge) Resolved() (result string, ok bool) {
+	var b strings.Builder
+	for _, p := range m.parts {
+		if p.conflict == nil {
+			b.WriteString(p.text)
+			continue
+		}
+		if !p.conflict.resolved {
+			return "", false
+		}
+		b.WriteString(p.conflict.text)
+	}
+	return b.String(), true
+}
+
+// Unified renders the merge as a single string: auto-merged regions and
+// resolved conflicts appear as plain text, and any remaining unresolved
+// conflicts are rendered with `<<<<<<< ours` / `=======` / `>>>>>>> theirs`
+// markers (plus a `||||||| base` section when MergeOptions.ShowBase is set)
+func (m *Merge) Unified() (unified string) {
+	var b strings.Builder
+	for _, p := range m.parts {
+		if p.conflict == nil {
+			b.WriteString(p.text)
+			continue
+		}
+		c := p.conflict
+		if c.resolved {
+			b.WriteString(c.text)
+			continue
+		}
+		b.WriteString("<<<<<<< ours\n")
+		b.WriteString(c.Ours)
+		if m.opts.ShowBase {
+			b.WriteString("||||||| base\n")
+			b.WriteString(c.Base)
+		}
+		b.WriteString("=======\n")
+		b.WriteString(c.Theirs)
+		b.WriteString(">>>>>>> theirs\n")
+	}
+	unified = b.String()
+	return
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2024  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeAdjacentEditsAutoMerge(t *testing.T) {
+	base := "line1\nline2\nline3\nline4\n"
+	ours := "line1\nTWO\nline3\nline4\n"
+	theirs := "line1\nline2\nTHREE\nline4\n"
+
+	m := NewMerge("f.txt", base, ours, theirs)
+	if count := len(m.Conflicts()); count != 0 {
+		t.Fatalf("expected 0 conflicts for adjacent non-overlapping edits, got %d", count)
+	}
+	result, ok := m.Resolved()
+	if !ok {
+		t.Fatal("expected Resolved to succeed with no conflicts")
+	}
+	if want := "line1\nTWO\nTHREE\nline4\n"; result != want {
+		t.Fatalf("Resolved() = %q, want %q", result, want)
+	}
+}
+
+func TestMergeSeparatedEditsAutoMerge(t *testing.T) {
+	base := "line1\nline2\nline3\nline4\nline5\n"
+	ours := "line1\nTWO\nline3\nline4\nline5\n"
+	theirs := "line1\nline2\nline3\nline4\nFIVE\n"
+
+	m := NewMerge("f.txt", base, ours, theirs)
+	if count := len(m.Conflicts()); count != 0 {
+		t.Fatalf("expected 0 conflicts for edits separated by context, got %d", count)
+	}
+	result, ok := m.Resolved()
+	if !ok {
+		t.Fatal("expected Resolved to succeed with no conflicts")
+	}
+	if want := "line1\nTWO\nline3\nline4\nFIVE\n"; result != want {
+		t.Fatalf("Resolved() = %q, want %q", result, want)
+	}
+}
+
+func TestMergeOverlappingEditsConflict(t *testing.T) {
+	base := "line1\nline2\nline3\nline4\n"
+	ours := "line1\nTWO\nline3\nline4\n"
+	theirs := "line1\nDEUX\nline3\nline4\n"
+
+	m := NewMerge("f.txt", base, ours, theirs)
+	conflicts := m.Conflicts()
+	if len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict for incompatible overlapping edits, got %d", len(conflicts))
+	}
+	if conflicts[0].Ours != "TWO\n" || conflicts[0].Theirs != "DEUX\n" {
+		t.Fatalf("unexpected conflict content: %+v", conflicts[0])
+	}
+	if _, ok := m.Resolved(); ok {
+		t.Fatal("expected Resolved to fail before the conflict is resolved")
+	}
+	if !m.KeepOurs(0) {
+		t.Fatal("KeepOurs(0) should succeed")
+	}
+	result, ok := m.Resolved()
+	if !ok {
+		t.Fatal("expected Resolved to succeed after KeepOurs")
+	}
+	if want := "line1\nTWO\nline3\nline4\n"; result != want {
+		t.Fatalf("Resolved() = %q, want %q", result, want)
+	}
+}
+
+func TestMergeIdenticalEditsAutoMerge(t *testing.T) {
+	base := "line1\nline2\nline3\nline4\n"
+	ours := "line1\nSAME\nline3\nline4\n"
+	theirs := "line1\nSAME\nline3\nline4\n"
+
+	m := NewMerge("f.txt", base, ours, theirs)
+	if count := len(m.Conflicts()); count != 0 {
+		t.Fatalf("expected 0 conflicts when both sides make the same edit, got %d", count)
+	}
+	result, ok := m.Resolved()
+	if !ok || result != ours {
+		t.Fatalf("Resolved() = %q, %v, want %q, true", result, ok, ours)
+	}
+}
+
+func TestMergeUnifiedConflictMarkers(t *testing.T) {
+	base := "line1\nline2\nline3\n"
+	ours := "line1\nTWO\nline3\n"
+	theirs := "line1\nDEUX\nline3\n"
+
+	m := NewMerge("f.txt", base, ours, theirs)
+	unified := m.Unified()
+	want := "<<<<<<< ours\nTWO\n=======\nDEUX\n>>>>>>> theirs\n"
+	for _, part := range []string{"line1\n", want, "line3\n"} {
+		if !strings.Contains(unified, part) {
+			t.Fatalf("Unified() = %q, missing part %q", unified, part)
+		}
+	}
+}
@@ -16,6 +16,7 @@ package diff
 
 import (
 	"html"
+	"strings"
 )
 
 type renderBatch struct {
@@ -31,61 +32,141 @@ func (b *renderBatch) rem(line string) {
 	b.d = append(b.d, line)
 }
 
-func (r *CRender) processRenderDiffBatch(lastIdx int, lines *[]string, batch **renderBatch) {
-	if *batch == nil {
+func (r *CRender) prepareRenderDiff(path string, original []string) (lines []string) {
+	if r.Highlighter != nil {
+		// syntax highlighting colorizes every non-marker line as a whole,
+		// so intra-line add/remove pairing is skipped entirely
+		for idx, line := range original {
+			if idx < 2 || len(line) == 0 {
+				lines = append(lines, line)
+				continue
+			}
+			switch line[0] {
+			case '@', '\\', '#':
+				lines = append(lines, string(line[0])+html.EscapeString(line[1:]))
+			default:
+				lines = append(lines, string(line[0])+r.Highlighter(path, line[1:]))
+			}
+		}
 		return
 	}
 
-	if numDel := len((*batch).d); numDel > 0 {
-		if numAdd := len((*batch).a); numAdd > 0 {
-			for idx := range (*batch).d {
-				if idx < numAdd {
-					a, b := r.RenderLine((*batch).d[idx], (*batch).a[idx])
-					(*lines)[lastIdx-numDel-numAdd+idx] = "-" + a
-					(*lines)[lastIdx-numAdd+idx] = "+" + b
-				}
+	// render from the structured ChangeSet model so intra-line highlights
+	// come from LineChange.IntraEdits instead of a bespoke pairing pass
+	ok := walkUnifiedFiles(original, func(headerLines []string, hunks []Hunk) {
+		lines = append(lines, headerLines...)
+		for _, hunk := range hunks {
+			r.relinkIntraEdits(&hunk)
+			lines = append(lines, hunk.Header)
+			for _, hl := range hunk.Lines {
+				lines = append(lines, r.renderLineChange(hl))
 			}
 		}
+	})
+	if !ok {
+		return escapeRawLines(original)
 	}
-
-	*batch = nil
+	return
 }
 
-func (r *CRender) prepareRenderDiff(original []string) (lines []string) {
-	var batch *renderBatch
-	for idx, line := range original {
-		if idx < 2 {
-			// skip the patch header lines
-			lines = append(lines, line)
+// walkUnifiedFiles re-splits original against its own ParseUnified() result,
+// invoking fn once per file with that file's `--- `/`+++ ` header lines and
+// parsed Hunks. It reports false (without calling fn) if original can't be
+// parsed into any files, so callers can fall back to treating it as raw,
+// unstructured text. Shared by prepareRenderDiff, prepareRenderInline and
+// RenderSideBySide so there is exactly one file/header walk over a unified
+// diff's raw lines
+func walkUnifiedFiles(original []string, fn func(headerLines []string, hunks []Hunk)) bool {
+	files, err := ParseUnified(strings.Join(original, "\n"))
+	if err != nil || len(files) == 0 {
+		return false
+	}
+
+	fileIdx := 0
+	idx := 0
+	for idx < len(original) {
+		line := original[idx]
+		if !strings.HasPrefix(line, "--- ") || fileIdx >= len(files) {
+			idx++
 			continue
 		}
-		if len(line) == 0 {
-			lines = append(lines, "")
-			r.processRenderDiffBatch(idx, &lines, &batch)
-			continue
+
+		// preserve this file's `---`/`+++` header lines verbatim
+		headerLines := []string{line}
+		idx++
+		if idx < len(original) && strings.HasPrefix(original[idx], "+++ ") {
+			headerLines = append(headerLines, original[idx])
+			idx++
 		}
-		lines = append(lines, string(line[0])+html.EscapeString(line[1:]))
-		if batch == nil {
-			if line[0] == '-' {
-				// new batch starting
-				batch = &renderBatch{}
-				batch.rem(line[1:])
-			}
+
+		fn(headerLines, files[fileIdx].Hunks)
+		fileIdx++
+
+		// skip this file's raw hunk lines; the next `--- ` (or EOF) starts
+		// the following file
+		for idx < len(original) && !strings.HasPrefix(original[idx], "--- ") {
+			idx++
+		}
+	}
+	return true
+}
+
+// escapeRawLines is prepareRenderDiff's fallback for unified text that
+// ParseUnified couldn't split into any files (e.g. a bare hunk with no
+// `--- `/`+++ ` headers): each line's content is HTML-escaped, keeping any
+// leading diff marker byte (`+`/`-`/`@`/`\`/`#`/` `) intact so RenderDiffFor
+// can still dispatch on it
+func escapeRawLines(original []string) []string {
+	lines := make([]string, len(original))
+	for idx, line := range original {
+		if line == "" {
 			continue
 		}
-		// batch in progress
-		if line[0] == '-' {
-			if len(batch.a) > 0 {
-				r.processRenderDiffBatch(idx, &lines, &batch)
-				batch = &renderBatch{}
-			}
-			batch.rem(line[1:])
-		} else if line[0] == '+' {
-			batch.add(line[1:])
-		} else {
-			r.processRenderDiffBatch(idx, &lines, &batch)
+		switch line[0] {
+		case '+', '-', '@', '\\', '#', ' ':
+			lines[idx] = string(line[0]) + html.EscapeString(line[1:])
+		default:
+			lines[idx] = html.EscapeString(line)
+		}
+	}
+	return lines
+}
+
+// renderLineChange renders a single LineChange's marker and content,
+// highlighting its IntraEdits (if any) with the Text.Add/Text.Rem markup
+func (r *CRender) renderLineChange(line LineChange) string {
+	if line.Kind == LineNote {
+		return "\\" + html.EscapeString(line.Text)
+	}
+
+	prefix := byte(' ')
+	switch line.Kind {
+	case LineAdd:
+		prefix = '+'
+	case LineRemove:
+		prefix = '-'
+	}
+
+	if len(line.IntraEdits) == 0 {
+		return string(prefix) + html.EscapeString(line.Text)
+	}
+
+	return string(prefix) + r.renderSegments(line.IntraEdits)
+}
+
+// renderSegments renders a slice of Segments, wrapping LineAdd/LineRemove
+// segments with the Text.Add/Text.Rem markup and HTML-escaping everything
+func (r *CRender) renderSegments(segments []Segment) (text string) {
+	for _, seg := range segments {
+		escaped := html.EscapeString(seg.Text)
+		switch seg.Kind {
+		case LineAdd:
+			text += r.Text.Add.Open + escaped + r.Text.Add.Close
+		case LineRemove:
+			text += r.Text.Rem.Open + escaped + r.Text.Rem.Close
+		default:
+			text += escaped
 		}
 	}
-	r.processRenderDiffBatch(len(original), &lines, &batch)
 	return
 }
@@ -0,0 +1,37 @@
+// Copyright (c) 2024  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import "testing"
+
+// TestPrepareRenderDiffEscapesUnparsableFallback covers the fallback
+// prepareRenderDiff takes when ParseUnified can't split the input into any
+// files (here, a bare hunk with no `--- `/`+++ ` headers): the raw lines
+// must still be HTML-escaped, not passed through verbatim
+func TestPrepareRenderDiffEscapesUnparsableFallback(t *testing.T) {
+	r := NewRenderer().Make().(*CRender)
+	original := []string{"@@ -1,2 +1,2 @@", "-<script>old</script>", "+<b>world</b>"}
+
+	lines := r.prepareRenderDiff("", original)
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %+v", len(lines), lines)
+	}
+	if lines[1] != "-&lt;script&gt;old&lt;/script&gt;" {
+		t.Fatalf("removed line not escaped: %q", lines[1])
+	}
+	if lines[2] != "+&lt;b&gt;world&lt;/b&gt;" {
+		t.Fatalf("added line not escaped: %q", lines[2])
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2024  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"unicode"
+)
+
+// Granularity specifies the token size RenderLine diffs intra-line changes
+// at
+type Granularity uint
+
+const (
+	// GranularityChar is the default, diffing with diffmatchpatch's own
+	// (already rune-safe) tokenization
+	GranularityChar Granularity = iota
+	// GranularityRune diffs one rune at a time, guaranteeing that markup
+	// never splits a multi-byte rune in two
+	GranularityRune
+	// GranularityWord diffs runs of non-whitespace and whitespace as single
+	// tokens, promoting intra-line changes to whole-word highlights
+	GranularityWord
+	// GranularityGrapheme diffs user-perceived characters (a base rune plus
+	// any combining marks or joiners that render as one glyph), avoiding
+	// broken markup around CJK, accents and emoji
+	GranularityGrapheme
+)
+
+// syntheticBase is the start of the Unicode Private Use Area used to map
+// arbitrary tokens onto a synthetic single-rune alphabet for diffmatchpatch
+const syntheticBase = 0xE000
+
+// tokensToAlphabet maps each token to a synthetic rune, reusing the same
+// rune for repeated tokens (including tokens seen in an earlier call
+// against the same seen/table pair), and returns the encoded sequence
+// alongside the table needed to decode it back into the original tokens
+func tokensToAlphabet(tokens []string, seen map[string]rune, table map[rune]string, next *rune) (encoded []rune) {
+	for _, tok := range tokens {
+		code, present := seen[tok]
+		if !present {
+			code = *next
+			*next++
+			seen[tok] = code
+			table[code] = tok
+		}
+		encoded = append(encoded, code)
+	}
+	return
+}
+
+// splitRunes tokenizes a string into one token per rune
+func splitRunes(s string) (tokens []string) {
+	for _, r := range s {
+		tokens = append(tokens, string(r))
+	}
+	return
+}
+
+// isCombining reports whether r is a mark or joiner that combines with the
+// preceding rune into a single user-perceived grapheme cluster
+func isCombining(r rune) bool {
+	if r == '‍' { // zero-width joiner
+		return true
+	}
+	if r >= '︀' && r <= '️' { // variation selectors
+		return true
+	}
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc)
+}
+
+// splitGraphemes tokenizes a string into approximate grapheme clusters: a
+// base rune followed by any combining marks, joiners or variation selectors
+func splitGraphemes(s string) (tokens []string) {
+	var cluster []rune
+	for _, r := range s {
+		if len(cluster) > 0 && isCombining(r) {
+			cluster = append(cluster, r)
+			continue
+		}
+		if len(cluster) > 0 {
+			tokens = append(tokens, string(cluster))
+		}
+		cluster = []rune{r}
+	}
+	if len(cluster) > 0 {
+		tokens = append(tokens, string(cluster))
+	}
+	return
+}
+
+// splitWords tokenizes a string into runs of whitespace and runs of
+// non-whitespace, so that joining the tokens reproduces the original string
+func splitWords(s string) (tokens []string) {
+	var run []rune
+	var runIsSpace bool
+	for idx, r := range s {
+		isSpace := unicode.IsSpace(r)
+		if idx == 0 {
+			run = append(run, r)
+			runIsSpace = isSpace
+			continue
+		}
+		if isSpace == runIsSpace {
+			run = append(run, r)
+			continue
+		}
+		tokens = append(tokens, string(run))
+		run = []rune{r}
+		runIsSpace = isSpace
+	}
+	if len(run) > 0 {
+		tokens = append(tokens, string(run))
+	}
+	return
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2024  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"html"
+	"strings"
+)
+
+// renderInlineLine combines a single-line diff into one stream, marking
+// removals and additions in place instead of splitting them onto two lines.
+// It diffs through r.diffSegments so the configured Granularity applies here
+// the same way it does to RenderLine, then walks the removed/added segment
+// lists in lockstep to reconstruct their original interleaved order: a
+// Remove is always drained from segA as soon as it's reached, an Add is
+// drained from segB the same way, and matching Context segments (present
+// in both) are consumed together
+func (r *CRender) renderInlineLine(a, b string) (line string) {
+	segA, segB := r.diffSegments(a, b)
+	i, j := 0, 0
+	for i < len(segA) || j < len(segB) {
+		switch {
+		case i < len(segA) && segA[i].Kind == LineRemove:
+			line += r.Text.Rem.Open + html.EscapeString(segA[i].Text) + r.Text.Rem.Close
+			i++
+		case j < len(segB) && segB[j].Kind == LineAdd:
+			line += r.Text.Add.Open + html.EscapeString(segB[j].Text) + r.Text.Add.Close
+			j++
+		case i < len(segA):
+			line += html.EscapeString(segA[i].Text)
+			i++
+			j++
+		default:
+			line += html.EscapeString(segB[j].Text)
+			j++
+		}
+	}
+	return
+}
+
+// markerPrefix returns the diff marker byte a lone (unpaired) LineChange
+// would have carried in raw unified diff text, so escaped single lines keep
+// dispatching through the same '+'/'-'/'@'/'\\' switch as everything else
+func markerPrefix(line LineChange) byte {
+	switch line.Kind {
+	case LineAdd:
+		return '+'
+	case LineRemove:
+		return '-'
+	case LineNote:
+		return '\\'
+	default:
+		return ' '
+	}
+}
+
+// renderInlineHunkLines turns one hunk's Lines into prepareRenderInline's
+// output lines: every paired removal/addition collapses into a single
+// renderInlineLine, any left-over unpaired removals/additions keep their
+// marker with escaped content, and context/note lines pass through escaped
+func (r *CRender) renderInlineHunkLines(lines []LineChange) (out []string) {
+	for _, g := range splitLineGroups(lines) {
+		if g.single != nil {
+			l := *g.single
+			out = append(out, string(markerPrefix(l))+html.EscapeString(l.Text))
+			continue
+		}
+		removed, added := g.removed, g.added
+		paired := len(removed)
+		if len(added) < paired {
+			paired = len(added)
+		}
+		for i := 0; i < paired; i++ {
+			out = append(out, r.renderInlineLine(removed[i].Text, added[i].Text))
+		}
+		for i := paired; i < len(removed); i++ {
+			out = append(out, "-"+html.EscapeString(removed[i].Text))
+		}
+		for i := paired; i < len(added); i++ {
+			out = append(out, "+"+html.EscapeString(added[i].Text))
+		}
+	}
+	return
+}
+
+// prepareRenderInline walks a unified diff the same way prepareRenderDiff
+// does, except each paired removal/addition collapses into a single merged
+// line instead of two, ready for word-diff style markup
+func (r *CRender) prepareRenderInline(original []string) (lines []string) {
+	ok := walkUnifiedFiles(original, func(headerLines []string, hunks []Hunk) {
+		lines = append(lines, headerLines...)
+		for _, hunk := range hunks {
+			lines = append(lines, hunk.Header)
+			lines = append(lines, r.renderInlineHunkLines(hunk.Lines)...)
+		}
+	})
+	if !ok {
+		return escapeRawLines(original)
+	}
+	return
+}
+
+func (r *CRender) RenderInline(unified string) (markup string) {
+	original := strings.Split(unified, "\n")
+	lines := r.prepareRenderInline(original)
+
+	for _, line := range lines {
+		if size := len(line); size > 0 {
+			switch line[0] {
+			case '+':
+				// unpaired line additions
+				markup += r.Line.Add.Open + line + r.Line.Add.Close
+			case '-':
+				// unpaired line removals
+				markup += r.Line.Rem.Open + line + r.Line.Rem.Close
+			case '@', '\\', '#':
+				// diff info, comments
+				markup += r.Comment.Open + line + r.Comment.Close
+			default:
+				// merged word-diff lines and unmodified lines
+				markup += r.Normal.Open + line + r.Normal.Close
+			}
+			markup += "\n"
+		}
+	}
+
+	markup = r.File.Open + markup + r.File.Close
+	return
+}
+
+// renderSideBySideHunkLines turns one hunk's Lines into row-aligned
+// left/right markup, mirroring renderInlineHunkLines's grouping but keeping
+// removals and additions in their own column instead of merging them
+func (r *CRender) renderSideBySideHunkLines(lines []LineChange) (left, right []string) {
+	for _, g := range splitLineGroups(lines) {
+		if g.single != nil {
+			l := *g.single
+			if l.Kind == LineAdd {
+				left = append(left, "")
+				right = append(right, r.Line.Add.Open+"+"+html.EscapeString(l.Text)+r.Line.Add.Close)
+				continue
+			}
+			m := r.Normal.Open + html.EscapeString(l.Text) + r.Normal.Close
+			left = append(left, m)
+			right = append(right, m)
+			continue
+		}
+		removed, added := g.removed, g.added
+		rows := len(removed)
+		if numAdd := len(added); numAdd > rows {
+			rows = numAdd
+		}
+		for idx := 0; idx < rows; idx++ {
+			var l, rr string
+			switch {
+			case idx < len(removed) && idx < len(added):
+				a, b := r.RenderLine(removed[idx].Text, added[idx].Text)
+				l = r.Line.Rem.Open + "-" + a + r.Line.Rem.Close
+				rr = r.Line.Add.Open + "+" + b + r.Line.Add.Close
+			case idx < len(removed):
+				l = r.Line.Rem.Open + "-" + html.EscapeString(removed[idx].Text) + r.Line.Rem.Close
+			default:
+				rr = r.Line.Add.Open + "+" + html.EscapeString(added[idx].Text) + r.Line.Add.Close
+			}
+			left = append(left, l)
+			right = append(right, rr)
+		}
+	}
+	return
+}
+
+// RenderSideBySide parses a unified diff and produces two row-aligned
+// slices of markup, one per column, suitable for a two-column review UI.
+// Removed lines appear on the left, added lines on the right, and context,
+// header and comment lines are duplicated onto both columns so that left[i]
+// and right[i] always refer to the same row
+func (r *CRender) RenderSideBySide(unified string) (left, right []string) {
+	original := strings.Split(unified, "\n")
+
+	ok := walkUnifiedFiles(original, func(headerLines []string, hunks []Hunk) {
+		for _, h := range headerLines {
+			left = append(left, h)
+			right = append(right, h)
+		}
+		for _, hunk := range hunks {
+			m := r.Comment.Open + hunk.Header + r.Comment.Close
+			left = append(left, m)
+			right = append(right, m)
+			l, rr := r.renderSideBySideHunkLines(hunk.Lines)
+			left = append(left, l...)
+			right = append(right, rr...)
+		}
+	})
+	if !ok {
+		lines := escapeRawLines(original)
+		return lines, append([]string(nil), lines...)
+	}
+	return
+}
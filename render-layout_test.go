@@ -0,0 +1,105 @@
+// Copyright (c) 2024  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+const multiFileUnified = "--- a/foo.txt\n+++ b/foo.txt\n@@ -1,1 +1,1 @@\n-hello\n+world\n" +
+	"--- a/bar.txt\n+++ b/bar.txt\n@@ -1,1 +1,1 @@\n-baz\n+qux\n"
+
+// TestRenderInlineMultiFileDoesNotSplice guards against the second file's
+// `--- `/`+++ ` headers getting swallowed into the first file's add/remove
+// batch and diffed as if they were content: tagging Text.Rem/Text.Add lets
+// us see exactly what renderInlineLine paired "hello" with, instead of
+// guessing from the untagged merged text
+func TestRenderInlineMultiFileDoesNotSplice(t *testing.T) {
+	r := NewRenderer().SetTextRemoved("[-", "-]").SetTextAdded("[+", "+]").Make()
+	markup := r.RenderInline(multiFileUnified)
+	want := "--- a/foo.txt\n+++ b/foo.txt\n@@ -1,1 +1,1 @@\n[-hell-][+w+]o[+rld+]\n" +
+		"--- a/bar.txt\n+++ b/bar.txt\n@@ -1,1 +1,1 @@\n[-baz-][+qux+]\n"
+	if markup != want {
+		t.Fatalf("RenderInline() = %q, want %q", markup, want)
+	}
+}
+
+// TestRenderSideBySideMultiFileDoesNotSplice is RenderInline's analogue for
+// the two-column layout, checking both the left and right columns
+func TestRenderSideBySideMultiFileDoesNotSplice(t *testing.T) {
+	r := NewRenderer().Make()
+	left, right := r.RenderSideBySide(multiFileUnified)
+
+	foundFooHeader, foundBarHeader := false, false
+	for _, l := range left {
+		if l == "--- a/foo.txt" {
+			foundFooHeader = true
+		}
+		if l == "--- a/bar.txt" {
+			foundBarHeader = true
+		}
+		if strings.Contains(l, "hellworld") || strings.Contains(l, "--++") {
+			t.Fatalf("left column contains spliced garbage: %q", l)
+		}
+	}
+	if !foundFooHeader || !foundBarHeader {
+		t.Fatalf("left column missing a file header: %+v", left)
+	}
+	for _, r := range right {
+		if strings.Contains(r, "hellworld") || strings.Contains(r, "--++") {
+			t.Fatalf("right column contains spliced garbage: %q", r)
+		}
+	}
+	if len(left) != len(right) {
+		t.Fatalf("left/right column lengths differ: %d vs %d", len(left), len(right))
+	}
+}
+
+// TestRenderInlineEscapesHTML checks that both the merged word-diff segment
+// and an unpaired addition escape HTML-significant characters
+func TestRenderInlineEscapesHTML(t *testing.T) {
+	r := NewRenderer().Make()
+	unified := "--- a/x.txt\n+++ b/x.txt\n@@ -1,1 +1,1 @@\n-safe\n+<script>world</script>\n"
+	markup := r.RenderInline(unified)
+	if strings.Contains(markup, "<script>") || strings.Contains(markup, "</script>") {
+		t.Fatalf("RenderInline() = %q, expected <script>/</script> to be escaped", markup)
+	}
+	if !strings.Contains(markup, "&lt;") || !strings.Contains(markup, "&gt;") {
+		t.Fatalf("RenderInline() = %q, expected escaped angle brackets", markup)
+	}
+}
+
+// TestRenderSideBySideEscapesHTML is TestRenderInlineEscapesHTML's
+// side-by-side counterpart
+func TestRenderSideBySideEscapesHTML(t *testing.T) {
+	r := NewRenderer().Make()
+	unified := "--- a/x.txt\n+++ b/x.txt\n@@ -1,1 +1,1 @@\n-safe\n+<script>world</script>\n"
+	_, right := r.RenderSideBySide(unified)
+	for _, l := range right {
+		if strings.Contains(l, "<script>") {
+			t.Fatalf("RenderSideBySide() right = %+v, expected <script> to be escaped", right)
+		}
+	}
+	found := false
+	for _, l := range right {
+		if strings.Contains(l, "&lt;script&gt;") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("RenderSideBySide() right = %+v, expected an escaped &lt;script&gt;", right)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2024  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"bufio"
+	"html"
+	"io"
+	"strings"
+)
+
+// RenderDiffStream reads a unified diff from in one line at a time and
+// writes its markup to w as soon as each add/remove batch closes, so only
+// the current hunk's batch (not the whole diff) is ever held in memory.
+// It renders the LayoutUnified form around a bufio.Scanner instead of a
+// []string built up front. Only the first two lines are taken as the
+// `---`/`+++` file header: unlike RenderDiffFor, the file headers of a
+// later file in a multi-file diff are not recognised and are batched as
+// removed/added lines
+func (r *CRender) RenderDiffStream(in io.Reader, w io.Writer) (err error) {
+	if _, err = io.WriteString(w, r.File.Open); err != nil {
+		return
+	}
+	defer func() {
+		if err == nil {
+			_, err = io.WriteString(w, r.File.Close)
+		}
+	}()
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var batch renderBatch
+	var headers []string
+	var path string
+
+	flush := func() error {
+		if len(batch.d) == 0 && len(batch.a) == 0 {
+			return nil
+		}
+		defer func() { batch = renderBatch{} }()
+
+		if r.Highlighter != nil {
+			for _, line := range batch.d {
+				if werr := writeStreamLine(w, r, "-"+r.Highlighter(path, line)); werr != nil {
+					return werr
+				}
+			}
+			for _, line := range batch.a {
+				if werr := writeStreamLine(w, r, "+"+r.Highlighter(path, line)); werr != nil {
+					return werr
+				}
+			}
+			return nil
+		}
+
+		paired := len(batch.d)
+		if len(batch.a) < paired {
+			paired = len(batch.a)
+		}
+		for i := 0; i < paired; i++ {
+			segA, segB := r.diffSegments(batch.d[i], batch.a[i])
+			batch.d[i], batch.a[i] = r.renderSegments(segA), r.renderSegments(segB)
+		}
+		for i, line := range batch.d {
+			if i >= paired {
+				line = html.EscapeString(line)
+			}
+			if werr := writeStreamLine(w, r, "-"+line); werr != nil {
+				return werr
+			}
+		}
+		for i, line := range batch.a {
+			if i >= paired {
+				line = html.EscapeString(line)
+			}
+			if werr := writeStreamLine(w, r, "+"+line); werr != nil {
+				return werr
+			}
+		}
+		return nil
+	}
+
+	lineNo := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNo++
+
+		if lineNo <= 2 {
+			if err = flush(); err != nil {
+				return
+			}
+			headers = append(headers, line)
+			if lineNo == 2 {
+				path = extractDiffPath(strings.Join(headers, "\n"))
+			}
+			if err = writeStreamLine(w, r, line); err != nil {
+				return
+			}
+			continue
+		}
+
+		switch {
+		case len(line) > 0 && line[0] == '-':
+			if len(batch.a) > 0 {
+				// a removal after additions starts a new batch
+				if err = flush(); err != nil {
+					return
+				}
+			}
+			batch.rem(line[1:])
+		case len(line) > 0 && line[0] == '+':
+			batch.add(line[1:])
+		case r.Highlighter != nil && len(line) > 0 && line[0] != '@' && line[0] != '\\' && line[0] != '#':
+			// a context line: the highlighter branch colorizes every
+			// non-marker line, not just add/remove batches
+			if err = flush(); err != nil {
+				return
+			}
+			if err = writeStreamLine(w, r, string(line[0])+r.Highlighter(path, line[1:])); err != nil {
+				return
+			}
+		default:
+			if err = flush(); err != nil {
+				return
+			}
+			escaped := line
+			if len(line) > 0 {
+				escaped = string(line[0]) + html.EscapeString(line[1:])
+			}
+			if err = writeStreamLine(w, r, escaped); err != nil {
+				return
+			}
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+	err = flush()
+	return
+}
+
+// writeStreamLine wraps a single already-marked-up diff line (its leading
+// `+`/`-`/`@`/`\`/`#` character, if any, decides the markup) and writes it
+// plus a trailing newline to w
+func writeStreamLine(w io.Writer, r *CRender, line string) (err error) {
+	open, closeTag := r.Normal.Open, r.Normal.Close
+	if len(line) > 0 {
+		switch line[0] {
+		case '+':
+			open, closeTag = r.Line.Add.Open, r.Line.Add.Close
+		case '-':
+			open, closeTag = r.Line.Rem.Open, r.Line.Rem.Close
+		case '@', '\\', '#':
+			open, closeTag = r.Comment.Open, r.Comment.Close
+		}
+	}
+	_, err = io.WriteString(w, open+line+closeTag+"\n")
+	return
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2024  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildStreamBenchDiff renders a unified diff with a single fixed-size hunk
+// (3 removed, 3 added lines) preceded by contextLines of unchanged content,
+// so the hunk stays constant size while the surrounding file grows
+func buildStreamBenchDiff(contextLines int) string {
+	var b strings.Builder
+	b.WriteString("--- a/bench.txt\n")
+	b.WriteString("+++ b/bench.txt\n")
+	b.WriteString(fmt.Sprintf("@@ -1,%d +1,%d @@\n", contextLines+3, contextLines+3))
+	for i := 0; i < contextLines; i++ {
+		b.WriteString(fmt.Sprintf(" context line %d\n", i))
+	}
+	b.WriteString("-old line one\n")
+	b.WriteString("-old line two\n")
+	b.WriteString("-old line three\n")
+	b.WriteString("+new line one\n")
+	b.WriteString("+new line two\n")
+	b.WriteString("+new line three\n")
+	return b.String()
+}
+
+// BenchmarkRenderDiffStream measures RenderDiffStream's allocation cost as
+// the surrounding file grows while the changed hunk stays a fixed 3/3 lines.
+// Allocs/op grow linearly with contextLines, since every line is scanned
+// and written on its own; what stays bounded by the hunk size is the batch
+// held in memory at any one time, not the allocation count
+func BenchmarkRenderDiffStream(b *testing.B) {
+	for _, contextLines := range []int{10, 100, 1000, 10000} {
+		unified := buildStreamBenchDiff(contextLines)
+		r := NewRenderer().Make()
+		b.Run(fmt.Sprintf("context=%d", contextLines), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var buf bytes.Buffer
+				if err := r.RenderDiffStream(strings.NewReader(unified), &buf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRenderDiff is RenderDiffStream's non-streaming counterpart over
+// the same inputs, for comparison: it builds the full []string of rendered
+// lines up front instead of flushing as it scans
+func BenchmarkRenderDiff(b *testing.B) {
+	for _, contextLines := range []int{10, 100, 1000, 10000} {
+		unified := buildStreamBenchDiff(contextLines)
+		r := NewRenderer().Make()
+		b.Run(fmt.Sprintf("context=%d", contextLines), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r.RenderDiff(unified)
+			}
+		})
+	}
+}
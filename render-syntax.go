@@ -0,0 +1,35 @@
+// Copyright (c) 2024  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import "strings"
+
+// extractDiffPath recovers the file path RenderDiff colorizes with, reading
+// it from the unified diff's `+++ b/path` header (falling back to the
+// `--- a/path` header) as produced by Diff.Unified
+func extractDiffPath(unified string) (path string) {
+	lines := strings.SplitN(unified, "\n", 3)
+	for _, prefix := range []string{"+++ ", "--- "} {
+		for _, line := range lines[:min(len(lines), 2)] {
+			if strings.HasPrefix(line, prefix) {
+				path = strings.TrimPrefix(line, prefix)
+				path = strings.TrimPrefix(path, "b/")
+				path = strings.TrimPrefix(path, "a/")
+				return
+			}
+		}
+	}
+	return
+}
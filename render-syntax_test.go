@@ -0,0 +1,47 @@
+// Copyright (c) 2024  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import "testing"
+
+func TestExtractDiffPathPrefersPlusPlusPlus(t *testing.T) {
+	unified := "--- a/old.go\n+++ b/new.go\n@@ -1,1 +1,1 @@\n-a\n+b\n"
+	if got := extractDiffPath(unified); got != "new.go" {
+		t.Fatalf("extractDiffPath() = %q, want %q", got, "new.go")
+	}
+}
+
+func TestExtractDiffPathFallsBackToDashDashDash(t *testing.T) {
+	unified := "--- a/old.go\n@@ -1,1 +1,1 @@\n-a\n+b\n"
+	if got := extractDiffPath(unified); got != "old.go" {
+		t.Fatalf("extractDiffPath() = %q, want %q", got, "old.go")
+	}
+}
+
+func TestExtractDiffPathStripsABPrefixes(t *testing.T) {
+	for _, path := range []string{"a/foo/bar.go", "b/foo/bar.go"} {
+		unified := "--- " + path + "\n@@ -1,1 +1,1 @@\n-a\n+b\n"
+		if got := extractDiffPath(unified); got != "foo/bar.go" {
+			t.Fatalf("extractDiffPath(%q) = %q, want %q", unified, got, "foo/bar.go")
+		}
+	}
+}
+
+func TestExtractDiffPathNoHeaderFound(t *testing.T) {
+	unified := "@@ -1,1 +1,1 @@\n-a\n+b\n"
+	if got := extractDiffPath(unified); got != "" {
+		t.Fatalf("extractDiffPath() = %q, want empty string", got)
+	}
+}
@@ -16,6 +16,7 @@ package diff
 
 import (
 	"html"
+	"io"
 	"strings"
 
 	"github.com/sergi/go-diff/diffmatchpatch"
@@ -37,6 +38,19 @@ type RenderBuilder interface {
 	SetLineRemoved(open, close string) RenderBuilder
 	// SetTextRemoved specifies the markup wrapping removals within a line
 	SetTextRemoved(open, close string) RenderBuilder
+	// SetLayout specifies the output layout RenderDiff renders, defaulting
+	// to LayoutUnified
+	SetLayout(mode Layout) RenderBuilder
+	// SetGranularity specifies the token size RenderLine diffs intra-line
+	// changes at, defaulting to GranularityChar
+	SetGranularity(mode Granularity) RenderBuilder
+	// SetSyntaxHighlighter specifies a function called with the file path
+	// and a line of content, returning that content marked up with
+	// language-aware syntax highlighting. When set, RenderDiff and
+	// RenderDiffFor colorize each non-marker line with it instead of
+	// plain HTML-escaping in the LayoutUnified layout; LayoutInline and
+	// LayoutSideBySide output is not highlighted
+	SetSyntaxHighlighter(fn func(path, line string) string) RenderBuilder
 	// Make returns the built Renderer instance
 	Make() Renderer
 }
@@ -48,20 +62,57 @@ type Renderer interface {
 	RenderLine(a, b string) (ma, mb string)
 	// RenderDiff parses a unified diff string and highlights the interesting
 	// details using the CRender.Line, CRender.Comment and CRender.Normal
-	// markup strings
+	// markup strings. When the Layout is LayoutInline or LayoutSideBySide,
+	// RenderDiff delegates to RenderInline or RenderSideBySide respectively
 	RenderDiff(unified string) (markup string)
+	// RenderDiffFor is RenderDiff with an explicit file path, used to drive
+	// the SyntaxHighlighter when the path can't be (or shouldn't be)
+	// inferred from the unified diff's `---`/`+++` headers
+	RenderDiffFor(path, unified string) (markup string)
+	// RenderInline parses a unified diff string and produces a single stream
+	// where intra-line additions and removals are marked in place, the way
+	// `git diff --word-diff` presents changes
+	RenderInline(unified string) (markup string)
+	// RenderSideBySide parses a unified diff string and produces paired
+	// left/right markup rows aligned by hunk, suitable for a two-column
+	// code review layout
+	RenderSideBySide(unified string) (left, right []string)
+	// RenderDiffStream is RenderDiff for very large diffs: it reads a
+	// unified diff from r line-by-line and writes markup to w as each
+	// add/remove batch closes, instead of buffering the whole diff and
+	// its markup in memory. It always renders the LayoutUnified form,
+	// regardless of the receiver's configured Layout
+	RenderDiffStream(r io.Reader, w io.Writer) error
 	// Clone returns a new RenderBuilder instance configured exactly the same
 	// as the one the Clone method is called upon
 	Clone() RenderBuilder
 }
 
+// Layout specifies the arrangement RenderDiff (and friends) render a unified
+// diff into
+type Layout uint
+
+const (
+	// LayoutUnified renders the traditional single-stream unified diff,
+	// marking entire added and removed lines
+	LayoutUnified Layout = iota
+	// LayoutInline renders a single stream with intra-line additions and
+	// removals marked in place, similar to `git diff --word-diff`
+	LayoutInline
+	// LayoutSideBySide renders a two-column layout of paired left/right rows
+	LayoutSideBySide
+)
+
 // CRender implements the RenderBuilder and Renderer interfaces
 type CRender struct {
-	File    MarkupTag
-	Normal  MarkupTag
-	Comment MarkupTag
-	Line    AddRemTags
-	Text    AddRemTags
+	File        MarkupTag
+	Normal      MarkupTag
+	Comment     MarkupTag
+	Line        AddRemTags
+	Text        AddRemTags
+	Layout      Layout
+	Granularity Granularity
+	Highlighter func(path, line string) string
 }
 
 // NewRenderer returns a new RenderBuilder instance
@@ -112,6 +163,21 @@ func (r *CRender) SetTextRemoved(open, close string) RenderBuilder {
 	return r
 }
 
+func (r *CRender) SetLayout(mode Layout) RenderBuilder {
+	r.Layout = mode
+	return r
+}
+
+func (r *CRender) SetGranularity(mode Granularity) RenderBuilder {
+	r.Granularity = mode
+	return r
+}
+
+func (r *CRender) SetSyntaxHighlighter(fn func(path, line string) string) RenderBuilder {
+	r.Highlighter = fn
+	return r
+}
+
 func (r *CRender) Make() Renderer {
 	return r
 }
@@ -122,90 +188,104 @@ func (r *CRender) Clone() RenderBuilder {
 }
 
 func (r *CRender) RenderLine(a, b string) (ma, mb string) {
-	dmp := diffmatchpatch.New()
-	diffs := dmp.DiffMain(a, b, false)
-	for _, diff := range diffs {
-		text := html.EscapeString(diff.Text)
-		switch diff.Type {
-		case diffmatchpatch.DiffDelete:
+	segA, segB := r.diffSegments(a, b)
+	for _, seg := range segA {
+		text := html.EscapeString(seg.Text)
+		if seg.Kind == LineRemove {
 			ma += r.Text.Rem.Open + text + r.Text.Rem.Close
-
-		case diffmatchpatch.DiffInsert:
-			mb += r.Text.Add.Open + text + r.Text.Add.Close
-
-		case diffmatchpatch.DiffEqual:
-			fallthrough
-		default:
+		} else {
 			ma += text
+		}
+	}
+	for _, seg := range segB {
+		text := html.EscapeString(seg.Text)
+		if seg.Kind == LineAdd {
+			mb += r.Text.Add.Open + text + r.Text.Add.Close
+		} else {
 			mb += text
 		}
 	}
 	return
 }
 
-func (r *CRender) processRenderDiffBatch(lastIdx int, lines *[]string, batch **renderBatch) {
-	if *batch == nil {
-		return
+// diffSegments diffs two lines at the configured Granularity, returning the
+// removed side's Segments (LineRemove/LineContext) and the added side's
+// Segments (LineAdd/LineContext)
+func (r *CRender) diffSegments(a, b string) (segA, segB []Segment) {
+	switch r.Granularity {
+	case GranularityRune:
+		return tokenDiffSegments(splitRunes(a), splitRunes(b))
+	case GranularityWord:
+		return tokenDiffSegments(splitWords(a), splitWords(b))
+	case GranularityGrapheme:
+		return tokenDiffSegments(splitGraphemes(a), splitGraphemes(b))
 	}
 
-	if numDel := len((*batch).d); numDel > 0 {
-		if numAdd := len((*batch).a); numAdd > 0 {
-			for idx := range (*batch).d {
-				if idx < numAdd {
-					a, b := r.RenderLine((*batch).d[idx], (*batch).a[idx])
-					(*lines)[lastIdx-numDel-numAdd+idx] = "-" + a
-					(*lines)[lastIdx-numAdd+idx] = "+" + b
-				}
-			}
+	dmp := diffmatchpatch.New()
+	for _, d := range dmp.DiffMain(a, b, false) {
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			segA = append(segA, Segment{Kind: LineRemove, Text: d.Text})
+		case diffmatchpatch.DiffInsert:
+			segB = append(segB, Segment{Kind: LineAdd, Text: d.Text})
+		case diffmatchpatch.DiffEqual:
+			fallthrough
+		default:
+			segA = append(segA, Segment{Kind: LineContext, Text: d.Text})
+			segB = append(segB, Segment{Kind: LineContext, Text: d.Text})
 		}
 	}
-
-	*batch = nil
+	return
 }
 
-func (r *CRender) prepareRenderDiff(original []string) (lines []string) {
-	var batch *renderBatch
-	for idx, line := range original {
-		if idx < 2 {
-			// skip the patch header lines
-			lines = append(lines, line)
-			continue
-		}
-		size := len(line)
-		if size == 0 {
-			lines = append(lines, "")
-			r.processRenderDiffBatch(idx, &lines, &batch)
-			continue
-		}
-		lines = append(lines, string(line[0])+html.EscapeString(line[1:]))
-		if batch == nil {
-			if line[0] == '-' {
-				// new batch starting
-				batch = &renderBatch{}
-				batch.rem(line[1:])
-			}
-			continue
+// tokenDiffSegments diffs two pre-tokenized sequences by mapping each token
+// onto a synthetic single-rune alphabet, running diffmatchpatch over the
+// resulting rune sequences, and remapping the result back to the original
+// tokens. This keeps diffs from ever splitting a token (a rune, a grapheme
+// cluster or a word) across an add/remove boundary
+func tokenDiffSegments(a, b []string) (segA, segB []Segment) {
+	seen := make(map[string]rune, len(a)+len(b))
+	table := make(map[rune]string, len(a)+len(b))
+	next := rune(syntheticBase)
+	encodedA := tokensToAlphabet(a, seen, table, &next)
+	encodedB := tokensToAlphabet(b, seen, table, &next)
+
+	dmp := diffmatchpatch.New()
+	for _, d := range dmp.DiffMainRunes(encodedA, encodedB, false) {
+		var text string
+		for _, code := range d.Text {
+			text += table[code]
 		}
-		// batch in progress
-		if line[0] == '-' {
-			if len(batch.a) > 0 {
-				r.processRenderDiffBatch(idx, &lines, &batch)
-				batch = &renderBatch{}
-			}
-			batch.rem(line[1:])
-		} else if line[0] == '+' {
-			batch.add(line[1:])
-		} else {
-			r.processRenderDiffBatch(idx, &lines, &batch)
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			segA = append(segA, Segment{Kind: LineRemove, Text: text})
+		case diffmatchpatch.DiffInsert:
+			segB = append(segB, Segment{Kind: LineAdd, Text: text})
+		case diffmatchpatch.DiffEqual:
+			fallthrough
+		default:
+			segA = append(segA, Segment{Kind: LineContext, Text: text})
+			segB = append(segB, Segment{Kind: LineContext, Text: text})
 		}
 	}
-	r.processRenderDiffBatch(len(original), &lines, &batch)
 	return
 }
 
 func (r *CRender) RenderDiff(unified string) (markup string) {
+	return r.RenderDiffFor(extractDiffPath(unified), unified)
+}
+
+func (r *CRender) RenderDiffFor(path, unified string) (markup string) {
+	switch r.Layout {
+	case LayoutInline:
+		return r.RenderInline(unified)
+	case LayoutSideBySide:
+		left, right := r.RenderSideBySide(unified)
+		return strings.Join(left, "\n") + "\n" + strings.Join(right, "\n")
+	}
+
 	original := strings.Split(unified, "\n")
-	lines := r.prepareRenderDiff(original)
+	lines := r.prepareRenderDiff(path, original)
 
 	for _, line := range lines {
 		if size := len(line); size > 0 {
@@ -0,0 +1,140 @@
+// Copyright (c) 2024  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"strings"
+
+	"github.com/hexops/gotextdiff"
+)
+
+// WhitespaceMode is a set of bit flags selecting which whitespace
+// differences Options.IgnoreWhitespace treats as insignificant, mirroring
+// git's --ignore-all-space, --ignore-space-change, --ignore-blank-lines and
+// --ignore-cr-at-eol diff flags
+type WhitespaceMode uint8
+
+const (
+	// IgnoreAllSpace ignores all whitespace (spaces and tabs) when comparing
+	// lines, equivalent to git's --ignore-all-space
+	IgnoreAllSpace WhitespaceMode = 1 << iota
+	// IgnoreSpaceChange treats any run of spaces and tabs as equivalent to
+	// any other, and ignores trailing whitespace, equivalent to git's
+	// --ignore-space-change. Has no effect when IgnoreAllSpace is also set
+	IgnoreSpaceChange
+	// IgnoreBlankLines treats all blank (whitespace-only) lines as
+	// equivalent to one another. Unlike git's --ignore-blank-lines, blank
+	// lines that were inserted or deleted are not hidden and still show as
+	// edits
+	IgnoreBlankLines
+	// IgnoreCRAtEOL ignores a trailing carriage return at the end of a
+	// line, equivalent to git's --ignore-cr-at-eol
+	IgnoreCRAtEOL
+)
+
+// normalizeWhitespace rewrites text one line at a time according to mode,
+// preserving the exact number of lines (and their newlines) so the result
+// lines up 1:1 with the original for edit-discovery purposes
+func normalizeWhitespace(text string, mode WhitespaceMode) string {
+	lines := splitLines(text)
+	for i, line := range lines {
+		nl := ""
+		content := line
+		if strings.HasSuffix(content, "\n") {
+			nl = "\n"
+			content = content[:len(content)-1]
+		}
+		if mode&IgnoreCRAtEOL != 0 {
+			content = strings.TrimSuffix(content, "\r")
+		}
+		switch {
+		case mode&IgnoreAllSpace != 0:
+			content = stripAllSpace(content)
+		case mode&IgnoreSpaceChange != 0:
+			content = collapseSpace(content)
+		}
+		if mode&IgnoreBlankLines != 0 && strings.TrimSpace(content) == "" {
+			content = ""
+		}
+		lines[i] = content + nl
+	}
+	return strings.Join(lines, "")
+}
+
+func stripAllSpace(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r == ' ' || r == '\t' {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func collapseSpace(s string) string {
+	var b strings.Builder
+	inSpace := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' {
+			inSpace = true
+			continue
+		}
+		if inSpace {
+			b.WriteByte(' ')
+			inSpace = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// restoreOriginalText rewrites edits computed against normalized text so
+// their NewText comes from the real, unnormalized changed text instead.
+// Edit Spans already address the original source's line numbers unchanged,
+// since normalizeWhitespace never adds or removes lines; only the inserted
+// content needs to be swapped back to what changed actually contains
+func restoreOriginalText(edits []gotextdiff.TextEdit, source, changed string) []gotextdiff.TextEdit {
+	bLines := splitLines(changed)
+	aPos, bPos := 0, 0
+	for i, edit := range edits {
+		aStart := edit.Span.Start().Line() - 1
+		bPos += aStart - aPos
+		aPos = aStart
+
+		insCount := countLines(edit.NewText)
+		bStart := bPos
+		bEnd := bStart + insCount
+		if bEnd > len(bLines) {
+			bEnd = len(bLines)
+		}
+		if insCount > 0 {
+			edits[i].NewText = strings.Join(bLines[bStart:bEnd], "")
+		}
+
+		aPos = edit.Span.End().Line() - 1
+		bPos = bEnd
+	}
+	return edits
+}
+
+// countLines returns how many lines text splits into, consistent with
+// splitLines, with the empty string correctly counting as zero lines
+func countLines(text string) int {
+	if text == "" {
+		return 0
+	}
+	return len(splitLines(text))
+}
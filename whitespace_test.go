@@ -0,0 +1,77 @@
+// Copyright (c) 2024  The Go-Curses Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeWhitespaceIgnoreAllSpace(t *testing.T) {
+	got := normalizeWhitespace("a b\tc\n no  spaces\n", IgnoreAllSpace)
+	if want := "abc\nnospaces\n"; got != want {
+		t.Fatalf("normalizeWhitespace() = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeWhitespaceIgnoreSpaceChange(t *testing.T) {
+	got := normalizeWhitespace("a   b\tc\ntrailing   \n", IgnoreSpaceChange)
+	if want := "a b c\ntrailing\n"; got != want {
+		t.Fatalf("normalizeWhitespace() = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeWhitespaceIgnoreBlankLines(t *testing.T) {
+	got := normalizeWhitespace("a\n   \nb\n\n", IgnoreBlankLines)
+	if want := "a\n\nb\n\n"; got != want {
+		t.Fatalf("normalizeWhitespace() = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeWhitespaceIgnoreCRAtEOL(t *testing.T) {
+	got := normalizeWhitespace("a\r\nb\n", IgnoreCRAtEOL)
+	if want := "a\nb\n"; got != want {
+		t.Fatalf("normalizeWhitespace() = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeWhitespacePreservesLineCount(t *testing.T) {
+	text := "a\nb  \n\nc\n"
+	got := normalizeWhitespace(text, IgnoreAllSpace|IgnoreBlankLines|IgnoreCRAtEOL)
+	if countLines(got) != countLines(text) {
+		t.Fatalf("normalizeWhitespace() changed line count: %q -> %q", text, got)
+	}
+}
+
+// TestDiffIgnoreWhitespaceRestoresOriginalText checks the full
+// Options.IgnoreWhitespace path: a whitespace-only change is invisible to
+// edit discovery, but a genuine content change in the same diff still
+// carries its exact, unnormalized NewText through restoreOriginalText
+func TestDiffIgnoreWhitespaceRestoresOriginalText(t *testing.T) {
+	source := "line1\nline2\nline3\n"
+	changed := "line1\nline2  \nCHANGED\n"
+
+	d := NewWithOptions("f.txt", source, changed, Options{IgnoreWhitespace: IgnoreSpaceChange})
+	unified, err := d.Unified()
+	if err != nil {
+		t.Fatalf("Unified: %v", err)
+	}
+	if want := "-line3\n+CHANGED"; !strings.Contains(unified, want) {
+		t.Fatalf("Unified() = %q, expected it to contain %q", unified, want)
+	}
+	if strings.Contains(unified, "-line2") || strings.Contains(unified, "+line2") {
+		t.Fatalf("Unified() = %q, whitespace-only change on line2 should not appear as an edit", unified)
+	}
+}